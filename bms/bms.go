@@ -0,0 +1,117 @@
+// Package bms defines the vendor-agnostic interface that conductor uses to
+// talk to battery management systems over BLE, along with the shared data
+// shape every driver parses its responses into.
+package bms
+
+import (
+	"fmt"
+
+	"tinygo.org/x/bluetooth"
+)
+
+// Driver implements the BLE command/response protocol for one family of BMS
+// hardware. Vendors differ in their service/characteristic UUIDs, command
+// encoding, and response layout, but all of that stays behind this interface
+// so the connect loop never needs to know which BMS it's talking to.
+type Driver interface {
+	// Commands returns the named command frames this driver can send, e.g.
+	// "GET_BATTERY_INFO" -> the raw bytes to write to the characteristic.
+	Commands() map[string][]byte
+
+	// Parse decodes a reassembled response frame for the named command.
+	// The concrete type returned depends on name; GET_BATTERY_INFO drivers
+	// should return a *BatteryInfo.
+	Parse(name string, frame []byte) (any, error)
+
+	// CommandID extracts the command-id byte a Framer should match a
+	// response against from the raw command bytes Commands() returned.
+	// Its position within cmd is vendor-specific (the PQ protocol carries
+	// it at a different offset than JBD's), so callers must go through
+	// this rather than indexing cmd directly. ok is false if cmd is too
+	// short to contain one.
+	CommandID(cmd []byte) (id byte, ok bool)
+
+	// Identify reports whether a scan result looks like a device this
+	// driver can talk to, so the connect loop can pick a driver without
+	// being told the device's vendor up front.
+	Identify(device bluetooth.ScanResult) bool
+
+	// ServiceUUID and CharUUID are the BLE service and characteristic the
+	// driver expects to write commands to and receive notifications on.
+	ServiceUUID() bluetooth.UUID
+	CharUUID() bluetooth.UUID
+
+	// NewFramer returns a Framer that reassembles this driver's frame
+	// layout, invoking onFrame (if non-nil) for every frame it validates.
+	// Frame layout, headers, and checksums are vendor-specific, so each
+	// driver supplies its own rather than the connect loop assuming one
+	// fixed wire format for every BMS family.
+	NewFramer(onFrame func(Frame)) Framer
+
+	// New returns a fresh Driver instance for a single connection. Callers
+	// should use it once a device has been identified, so that any
+	// per-connection state a driver keeps (e.g. a firmware version it
+	// learned from a GET_VERSION response) isn't shared across devices
+	// connected through the same registered driver.
+	New() Driver
+}
+
+// BatteryInfo is the normalized battery telemetry produced by drivers that
+// support a GET_BATTERY_INFO command. Field availability may vary slightly
+// by vendor, but the shape is shared so downstream consumers (exporters,
+// MQTT publishers, ...) don't need to know which driver produced it.
+type BatteryInfo struct {
+	PackVoltage          int
+	Voltage              int
+	BatteryPack          map[int]float64
+	Current              float64
+	Watt                 float64
+	RemainAh             float64
+	FactoryAh            float64
+	CellTemperature      int
+	MosfetTemperature    int
+	Heat                 string
+	DischargeSwitchState int
+	ProtectState         string
+	FailureState         []byte
+	EquilibriumState     int
+	BatteryState         int
+	SOC                  int
+	SOH                  int
+	DischargesCount      int
+	DischargesAHCount    int
+	BatteryStatus        string
+	BalanceStatus        string
+	CellStatus           string
+	HeatStatus           string
+}
+
+var registry = make(map[string]Driver)
+
+// Register makes a driver available under name for later lookup. It is
+// meant to be called from a driver package's init() function, following the
+// same pattern as database/sql drivers.
+func Register(name string, d Driver) {
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("bms: Register called twice for driver %q", name))
+	}
+	registry[name] = d
+}
+
+// Lookup returns the driver registered under name, if any.
+func Lookup(name string) (Driver, bool) {
+	d, ok := registry[name]
+	return d, ok
+}
+
+// Identify returns a registered driver that claims the scan result, or nil
+// if none do. Drivers are tried in map order, so which one is returned when
+// several claim the same device is not defined.
+func Identify(device bluetooth.ScanResult) Driver {
+	for _, d := range registry {
+		if d.Identify(device) {
+			return d
+		}
+	}
+	return nil
+}
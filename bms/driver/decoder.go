@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Decoder provides panic-free, bounds-checked reads over a fixed response
+// payload. It replaces the old approach of slicing fixed offsets directly
+// and calling log.Fatalf on a length mismatch, which crashed the whole
+// process on a short or malformed frame -- unacceptable for a daemon that's
+// supposed to keep polling after one bad read.
+type Decoder struct {
+	data []byte
+}
+
+// NewDecoder wraps data for bounds-checked reads.
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// ReadBytes returns data[offset : offset+length], or io.ErrUnexpectedEOF if
+// that range falls outside data.
+func (d *Decoder) ReadBytes(offset, length int) ([]byte, error) {
+	if offset < 0 || length < 0 || offset+length > len(d.data) {
+		return nil, fmt.Errorf("driver: reading %d bytes at offset %d from a %d-byte frame: %w", length, offset, len(d.data), io.ErrUnexpectedEOF)
+	}
+	return d.data[offset : offset+length], nil
+}
+
+// ReadU16 reads a big-endian uint16 at offset.
+func (d *Decoder) ReadU16(offset int) (uint16, error) {
+	b, err := d.ReadBytes(offset, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// ReadI16 reads a big-endian int16 at offset.
+func (d *Decoder) ReadI16(offset int) (int16, error) {
+	u, err := d.ReadU16(offset)
+	if err != nil {
+		return 0, err
+	}
+	return int16(u), nil
+}
+
+// ReadU32 reads a big-endian uint32 at offset.
+func (d *Decoder) ReadU32(offset int) (uint32, error) {
+	b, err := d.ReadBytes(offset, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// ReadU32Reversed reads a uint32 at offset the way the PQ protocol encodes
+// PackVoltage and Voltage: the 4 bytes reversed relative to normal
+// big-endian order (equivalent to reading them little-endian).
+func (d *Decoder) ReadU32Reversed(offset int) (uint32, error) {
+	b, err := d.ReadBytes(offset, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(reverseBytes(b)), nil
+}
+
+// ReadU16Reversed reads a uint16 at offset with its 2 bytes swapped, the
+// way the PQ protocol encodes per-cell voltages.
+func (d *Decoder) ReadU16Reversed(offset int) (uint16, error) {
+	b, err := d.ReadBytes(offset, 2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(reverseBytes(b)), nil
+}
@@ -0,0 +1,92 @@
+package driver
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDecoderReadBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		offset  int
+		length  int
+		wantErr bool
+	}{
+		{"exact fit", []byte{1, 2, 3, 4}, 0, 4, false},
+		{"within bounds", []byte{1, 2, 3, 4}, 1, 2, false},
+		{"empty data", nil, 0, 1, true},
+		{"past end", []byte{1, 2, 3}, 2, 2, true},
+		{"negative offset", []byte{1, 2, 3}, -1, 1, true},
+		{"negative length", []byte{1, 2, 3}, 0, -1, true},
+		{"zero length at end", []byte{1, 2, 3}, 3, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(tt.data)
+			got, err := d.ReadBytes(tt.offset, tt.length)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ReadBytes(%d, %d): got nil error, want error", tt.offset, tt.length)
+				}
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("ReadBytes(%d, %d): err = %v, want wrapping io.ErrUnexpectedEOF", tt.offset, tt.length, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadBytes(%d, %d): unexpected error: %v", tt.offset, tt.length, err)
+			}
+			if len(got) != tt.length {
+				t.Errorf("ReadBytes(%d, %d): got %d bytes, want %d", tt.offset, tt.length, len(got), tt.length)
+			}
+		})
+	}
+}
+
+func TestDecoderReadU16TruncatedFrame(t *testing.T) {
+	d := NewDecoder([]byte{0x01})
+	if _, err := d.ReadU16(0); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ReadU16 on a 1-byte frame: err = %v, want wrapping io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecoderReadU32TruncatedFrame(t *testing.T) {
+	d := NewDecoder([]byte{0x01, 0x02, 0x03})
+	if _, err := d.ReadU32(0); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ReadU32 on a 3-byte frame: err = %v, want wrapping io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecoderReadOnEmptyFrame(t *testing.T) {
+	d := NewDecoder(nil)
+
+	if _, err := d.ReadU16(0); err == nil {
+		t.Error("ReadU16 on an empty frame: got nil error, want error")
+	}
+	if _, err := d.ReadI16(0); err == nil {
+		t.Error("ReadI16 on an empty frame: got nil error, want error")
+	}
+	if _, err := d.ReadU32(0); err == nil {
+		t.Error("ReadU32 on an empty frame: got nil error, want error")
+	}
+	if _, err := d.ReadU32Reversed(0); err == nil {
+		t.Error("ReadU32Reversed on an empty frame: got nil error, want error")
+	}
+	if _, err := d.ReadU16Reversed(0); err == nil {
+		t.Error("ReadU16Reversed on an empty frame: got nil error, want error")
+	}
+}
+
+func TestDecoderReadU16Reversed(t *testing.T) {
+	d := NewDecoder([]byte{0x34, 0x12})
+	got, err := d.ReadU16Reversed(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 0x1234 {
+		t.Errorf("ReadU16Reversed = %#04x, want 0x1234", got)
+	}
+}
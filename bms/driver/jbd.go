@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"fmt"
+	"strings"
+
+	"tinygo.org/x/bluetooth"
+
+	"conductor/bms"
+)
+
+func init() {
+	bms.Register("JBD", NewJBD())
+}
+
+// JBD drives Jiabaida/"Overkill Solar" style smart BMS boards. The
+// well-known service/characteristic UUIDs, command set and frame
+// reassembly are wired up; Parse does not decode responses and returns an
+// error for every command.
+type JBD struct{}
+
+// NewJBD returns a ready-to-use JBD driver.
+func NewJBD() *JBD {
+	return &JBD{}
+}
+
+// New implements bms.Driver.
+func (j *JBD) New() bms.Driver {
+	return NewJBD()
+}
+
+// NewFramer implements bms.Driver.
+func (j *JBD) NewFramer(onFrame func(bms.Frame)) bms.Framer {
+	return NewJBDFramer(onFrame)
+}
+
+// CommandID implements bms.Driver. The JBD protocol carries the command id
+// at offset 2 of the outgoing command, e.g. "DDA50300FFFD77" -> 0x03.
+func (j *JBD) CommandID(cmd []byte) (byte, bool) {
+	if len(cmd) < 3 {
+		return 0, false
+	}
+	return cmd[2], true
+}
+
+var jbdServiceUUID = bluetooth.New16BitUUID(0xff00)
+var jbdCharUUID = bluetooth.New16BitUUID(0xff02)
+
+var jbdCommands = map[string][]byte{
+	"GET_BATTERY_INFO": {0xDD, 0xA5, 0x03, 0x00, 0xFF, 0xFD, 0x77},
+	"GET_CELL_INFO":    {0xDD, 0xA5, 0x04, 0x00, 0xFF, 0xFC, 0x77},
+}
+
+// ServiceUUID implements bms.Driver.
+func (j *JBD) ServiceUUID() bluetooth.UUID { return jbdServiceUUID }
+
+// CharUUID implements bms.Driver.
+func (j *JBD) CharUUID() bluetooth.UUID { return jbdCharUUID }
+
+// Identify implements bms.Driver.
+func (j *JBD) Identify(device bluetooth.ScanResult) bool {
+	name := device.LocalName()
+	return strings.HasPrefix(name, "JBD") || strings.Contains(name, "xiaoxiang")
+}
+
+// Commands implements bms.Driver.
+func (j *JBD) Commands() map[string][]byte {
+	out := make(map[string][]byte, len(jbdCommands))
+	for name, cmd := range jbdCommands {
+		out[name] = cmd
+	}
+	return out
+}
+
+// Parse implements bms.Driver. JBD frame decoding isn't implemented yet.
+func (j *JBD) Parse(name string, frame []byte) (any, error) {
+	return nil, fmt.Errorf("driver: JBD parsing for %q not implemented yet", name)
+}
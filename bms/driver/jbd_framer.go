@@ -0,0 +1,129 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"conductor/bms"
+)
+
+// JBDFramer reassembles JBD-protocol BLE notification fragments into
+// complete, checksum-verified frames.
+//
+// Frame layout (per the published Xiaoxiang/JBD smart BMS protocol, not
+// yet verified against a real pack): DD <cmd> <status> <len> <len bytes of
+// payload> <checksum hi> <checksum lo> 77, where checksum is the 16-bit
+// two's complement of (len + sum of payload bytes).
+type JBDFramer struct {
+	mu      sync.Mutex
+	buf     []byte
+	onFrame func(bms.Frame)
+	waiters map[byte][]chan []byte
+}
+
+// NewJBDFramer returns a JBDFramer that invokes onFrame (if non-nil) for
+// every frame it successfully reassembles and validates.
+func NewJBDFramer(onFrame func(bms.Frame)) *JBDFramer {
+	return &JBDFramer{
+		onFrame: onFrame,
+		waiters: make(map[byte][]chan []byte),
+	}
+}
+
+// Feed implements bms.Framer.
+func (f *JBDFramer) Feed(data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.buf = append(f.buf, data...)
+	for {
+		frame, consumed, ok := extractJBDFrame(f.buf)
+		if !ok {
+			if consumed > 0 {
+				f.buf = f.buf[consumed:]
+			}
+			return
+		}
+		f.buf = f.buf[consumed:]
+		f.deliver(frame)
+	}
+}
+
+// deliver must be called with f.mu held.
+func (f *JBDFramer) deliver(frame bms.Frame) {
+	for _, ch := range f.waiters[frame.Command] {
+		ch <- frame.Payload
+	}
+	delete(f.waiters, frame.Command)
+
+	if f.onFrame != nil {
+		f.onFrame(frame)
+	}
+}
+
+// WaitForCommand implements bms.Framer.
+func (f *JBDFramer) WaitForCommand(ctx context.Context, cmd byte) ([]byte, error) {
+	ch := make(chan []byte, 1)
+
+	f.mu.Lock()
+	f.waiters[cmd] = append(f.waiters[cmd], ch)
+	f.mu.Unlock()
+
+	select {
+	case payload := <-ch:
+		return payload, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("driver: waiting for JBD response to command 0x%02x: %w", cmd, ctx.Err())
+	}
+}
+
+const (
+	jbdStart         = 0xDD
+	jbdEnd           = 0x77
+	jbdFrameFixedLen = 7 // DD <cmd> <status> <len> ... <checksum hi> <checksum lo> 77, excluding payload
+)
+
+// extractJBDFrame looks for one complete, checksum-valid JBD frame at the
+// start of buf, the same way extractPQFrame does for the PQ protocol.
+func extractJBDFrame(buf []byte) (bms.Frame, int, bool) {
+	for i := 0; i+jbdFrameFixedLen <= len(buf); i++ {
+		if buf[i] != jbdStart {
+			continue
+		}
+
+		cmd := buf[i+1]
+		length := int(buf[i+3])
+		total := jbdFrameFixedLen + length
+
+		if i+total > len(buf) {
+			return bms.Frame{}, i, false
+		}
+
+		payload := buf[i+4 : i+4+length]
+		checksum := uint16(buf[i+4+length])<<8 | uint16(buf[i+4+length+1])
+		footer := buf[i+total-1]
+
+		if footer != jbdEnd || checksum != jbdChecksum(buf[i+3:i+4+length]) {
+			continue
+		}
+
+		frame := bms.Frame{Command: cmd, Payload: append([]byte(nil), payload...)}
+		return frame, i + total, true
+	}
+
+	if len(buf) > jbdFrameFixedLen {
+		return bms.Frame{}, len(buf) - jbdFrameFixedLen + 1, false
+	}
+	return bms.Frame{}, 0, false
+}
+
+// jbdChecksum is the 16-bit two's complement of the sum of data (the
+// length byte followed by the payload), per the JBD protocol.
+func jbdChecksum(data []byte) uint16 {
+	var sum uint16
+	for _, b := range data {
+		sum += uint16(b)
+	}
+	return uint16(0x10000 - uint32(sum))
+}
@@ -0,0 +1,101 @@
+package driver
+
+import "testing"
+
+// validJBDFrame builds a well-formed JBD frame for cmd carrying payload,
+// with a correct trailing checksum.
+func validJBDFrame(cmd byte, status byte, payload []byte) []byte {
+	frame := []byte{jbdStart, cmd, status, byte(len(payload))}
+	frame = append(frame, payload...)
+	checksum := jbdChecksum(append([]byte{byte(len(payload))}, payload...))
+	frame = append(frame, byte(checksum>>8), byte(checksum))
+	return append(frame, jbdEnd)
+}
+
+func TestExtractJBDFrameValid(t *testing.T) {
+	payload := []byte{0x01, 0x02, 0x03}
+	buf := validJBDFrame(0x03, 0x00, payload)
+
+	frame, consumed, ok := extractJBDFrame(buf)
+	if !ok {
+		t.Fatal("extractJBDFrame: got ok=false on a well-formed frame")
+	}
+	if consumed != len(buf) {
+		t.Errorf("consumed = %d, want %d", consumed, len(buf))
+	}
+	if frame.Command != 0x03 {
+		t.Errorf("Command = %#02x, want 0x03", frame.Command)
+	}
+	if string(frame.Payload) != string(payload) {
+		t.Errorf("Payload = %v, want %v", frame.Payload, payload)
+	}
+}
+
+func TestExtractJBDFrameIncomplete(t *testing.T) {
+	buf := validJBDFrame(0x03, 0x00, []byte{0x01, 0x02, 0x03})
+	_, _, ok := extractJBDFrame(buf[:len(buf)-2])
+	if ok {
+		t.Fatal("extractJBDFrame: got ok=true on a truncated frame")
+	}
+}
+
+func TestExtractJBDFrameBadChecksum(t *testing.T) {
+	buf := validJBDFrame(0x03, 0x00, []byte{0x01, 0x02, 0x03})
+	buf[len(buf)-2] ^= 0xFF // corrupt the checksum high byte
+
+	_, _, ok := extractJBDFrame(buf)
+	if ok {
+		t.Fatal("extractJBDFrame: got ok=true on a frame with a bad checksum")
+	}
+}
+
+func TestExtractJBDFrameBadFooter(t *testing.T) {
+	buf := validJBDFrame(0x03, 0x00, []byte{0x01, 0x02, 0x03})
+	buf[len(buf)-1] = 0x00 // not jbdEnd
+
+	_, _, ok := extractJBDFrame(buf)
+	if ok {
+		t.Fatal("extractJBDFrame: got ok=true on a frame with a bad footer byte")
+	}
+}
+
+func TestExtractJBDFrameGarbagePrefix(t *testing.T) {
+	// Noise ahead of a valid frame (e.g. a fragment of a PQ notification
+	// interleaved on the same characteristic) should be skipped rather
+	// than misread as part of the frame.
+	payload := []byte{0xAA, 0xBB}
+	valid := validJBDFrame(0x04, 0x00, payload)
+	buf := append([]byte{0x11, 0x22, jbdStart, 0x99}, valid...)
+
+	frame, consumed, ok := extractJBDFrame(buf)
+	if !ok {
+		t.Fatal("extractJBDFrame: got ok=false with garbage prefixed to a valid frame")
+	}
+	if consumed != len(buf) {
+		t.Errorf("consumed = %d, want %d", consumed, len(buf))
+	}
+	if frame.Command != 0x04 {
+		t.Errorf("Command = %#02x, want 0x04", frame.Command)
+	}
+}
+
+func TestExtractJBDFrameAllGarbage(t *testing.T) {
+	buf := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	frame, consumed, ok := extractJBDFrame(buf)
+	if ok {
+		t.Fatalf("extractJBDFrame: got ok=true on pure garbage, frame = %+v", frame)
+	}
+	if consumed < 0 || consumed > len(buf) {
+		t.Errorf("consumed = %d out of bounds for a %d-byte buffer", consumed, len(buf))
+	}
+}
+
+func TestExtractJBDFrameEmpty(t *testing.T) {
+	_, consumed, ok := extractJBDFrame(nil)
+	if ok {
+		t.Fatal("extractJBDFrame: got ok=true on an empty buffer")
+	}
+	if consumed != 0 {
+		t.Errorf("consumed = %d, want 0 for an empty buffer", consumed)
+	}
+}
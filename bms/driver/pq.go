@@ -0,0 +1,391 @@
+// Package driver holds the concrete bms.Driver implementations for BMS
+// vendor protocols conductor knows how to speak.
+package driver
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"tinygo.org/x/bluetooth"
+
+	"conductor/bms"
+)
+
+func init() {
+	bms.Register("PQ", NewPQ())
+}
+
+// PQ drives the "P-24100BNN160" family of BMS packs. A PQ instance tracks
+// the firmware version it last saw in a GET_VERSION response, so callers
+// must use New (or Identify + New) to get a dedicated instance per
+// connection rather than sharing the one registered in bms.
+type PQ struct {
+	firmwareVersion string
+}
+
+// NewPQ returns a ready-to-use PQ driver.
+func NewPQ() *PQ {
+	return &PQ{}
+}
+
+// New implements bms.Driver.
+func (p *PQ) New() bms.Driver {
+	return NewPQ()
+}
+
+// NewFramer implements bms.Driver.
+func (p *PQ) NewFramer(onFrame func(bms.Frame)) bms.Framer {
+	return NewPQFramer(onFrame)
+}
+
+// CommandID implements bms.Driver. The PQ protocol carries the command id
+// at offset 4 of the outgoing command, e.g. "000004011355AA17" -> 0x13.
+func (p *PQ) CommandID(cmd []byte) (byte, bool) {
+	if len(cmd) < 5 {
+		return 0, false
+	}
+	return cmd[4], true
+}
+
+var pqServiceUUID = bluetooth.New16BitUUID(0xffe0)
+var pqCharUUID = bluetooth.New16BitUUID(0xffe1)
+
+var pqCommands = map[string]string{
+	"GET_VERSION":      "000004011655AA1A",
+	"GET_BATTERY_INFO": "000004011355AA17",
+	"SERIAL_NUMBER":    "000004011055AA14",
+}
+
+// ServiceUUID implements bms.Driver.
+func (p *PQ) ServiceUUID() bluetooth.UUID { return pqServiceUUID }
+
+// CharUUID implements bms.Driver.
+func (p *PQ) CharUUID() bluetooth.UUID { return pqCharUUID }
+
+// Identify implements bms.Driver.
+func (p *PQ) Identify(device bluetooth.ScanResult) bool {
+	return strings.HasPrefix(device.LocalName(), "P-24100BNN160")
+}
+
+// Commands implements bms.Driver.
+func (p *PQ) Commands() map[string][]byte {
+	out := make(map[string][]byte, len(pqCommands))
+	for name, hexCmd := range pqCommands {
+		b, err := hex.DecodeString(hexCmd)
+		if err != nil {
+			// pqCommands is a package-level constant table; a decode
+			// failure here means the table itself is broken.
+			panic(fmt.Sprintf("driver: invalid PQ command %q: %v", name, err))
+		}
+		out[name] = b
+	}
+	return out
+}
+
+// Parse implements bms.Driver. frame is the framer-reassembled, checksum
+// verified response payload (header and trailing checksum already
+// stripped).
+func (p *PQ) Parse(name string, frame []byte) (any, error) {
+	switch name {
+	case "GET_BATTERY_INFO":
+		return parseBatteryInfo(frame, layoutFor(p.firmwareVersion))
+	case "GET_VERSION":
+		version, err := parseVersion(frame)
+		if err != nil {
+			return nil, err
+		}
+		p.firmwareVersion = version.Firmware
+		return fmt.Sprintf("firmware=%s manufactured=%s hardware=%s", version.Firmware, version.ManufactureDate, version.Hardware), nil
+	default:
+		return nil, fmt.Errorf("driver: PQ has no parser for command %q", name)
+	}
+}
+
+// reverseBytes reverses a byte slice, used to match the field layout the
+// PQ protocol reverses relative to wire order.
+func reverseBytes(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i, v := range data {
+		reversed[len(data)-i-1] = v
+	}
+	return reversed
+}
+
+// batteryInfoLayout holds the payload offsets parseBatteryInfo reads from.
+// PQ firmware revisions are known to shift these, so the offsets are kept
+// out of the parsing logic and dispatched on the firmware version learned
+// from a prior GET_VERSION response.
+type batteryInfoLayout struct {
+	packVoltage       int
+	voltage           int
+	cellPackStart     int
+	cellCount         int // number of 2-byte cell voltage slots
+	current           int
+	cellTemperature   int
+	mosfetTemperature int
+	remainAh          int
+	factoryAh         int
+	heat              int
+	protectState      int
+	failureState      int
+	equilibriumState  int
+	batteryState      int
+	soc               int
+	soh               int
+	dischargesCount   int
+	dischargesAHCount int
+}
+
+var defaultBatteryInfoLayout = batteryInfoLayout{
+	packVoltage:       0,
+	voltage:           4,
+	cellPackStart:     8,
+	cellCount:         16,
+	current:           40,
+	cellTemperature:   44,
+	mosfetTemperature: 46,
+	remainAh:          54,
+	factoryAh:         56,
+	heat:              60,
+	protectState:      68,
+	failureState:      72,
+	equilibriumState:  76,
+	batteryState:      80,
+	soc:               82,
+	soh:               84,
+	dischargesCount:   88,
+	dischargesAHCount: 92,
+}
+
+// firmwareLayouts maps a firmware version string, as reported by
+// GET_VERSION, to the battery-info layout it uses. Add an entry here once a
+// firmware revision with shifted offsets is confirmed against a real
+// capture; until then every version falls back to defaultBatteryInfoLayout.
+var firmwareLayouts = map[string]batteryInfoLayout{}
+
+// layoutFor returns the battery-info layout for firmwareVersion, falling
+// back to defaultBatteryInfoLayout for unknown or not-yet-learned versions.
+func layoutFor(firmwareVersion string) batteryInfoLayout {
+	if layout, ok := firmwareLayouts[firmwareVersion]; ok {
+		return layout
+	}
+	return defaultBatteryInfoLayout
+}
+
+// parseBatteryInfo decodes a GET_BATTERY_INFO response payload, i.e. the
+// frame body a bms.Framer has already stripped the 8-byte header and
+// trailing checksum byte from, using bounds-checked reads so a short or
+// malformed frame returns an error instead of crashing the process.
+func parseBatteryInfo(payload []byte, layout batteryInfoLayout) (*bms.BatteryInfo, error) {
+	d := NewDecoder(payload)
+	battery := &bms.BatteryInfo{
+		BatteryPack: make(map[int]float64),
+	}
+
+	packVoltage, err := d.ReadU32Reversed(layout.packVoltage)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ pack voltage: %w", err)
+	}
+	battery.PackVoltage = int(packVoltage)
+
+	voltage, err := d.ReadU32Reversed(layout.voltage)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ voltage: %w", err)
+	}
+	battery.Voltage = int(voltage)
+
+	for i := 0; i < layout.cellCount; i++ {
+		offset := layout.cellPackStart + i*2
+		cellVoltage, err := d.ReadU16Reversed(offset)
+		if err != nil {
+			return nil, fmt.Errorf("driver: PQ cell %d voltage: %w", i+1, err)
+		}
+		if cellVoltage == 0 {
+			continue
+		}
+		battery.BatteryPack[i+1] = float64(cellVoltage) / 1000
+	}
+
+	current, err := d.ReadU32(layout.current)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ current: %w", err)
+	}
+	// Current is signed: negative while the pack is discharging.
+	battery.Current = float64(int32(current)) / 1000
+
+	battery.Watt = math.Round((float64(battery.Voltage)*battery.Current)/10000*100) / 100
+
+	remainAh, err := d.ReadU16(layout.remainAh)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ remaining Ah: %w", err)
+	}
+	battery.RemainAh = float64(remainAh) / 100
+
+	factoryAh, err := d.ReadU16(layout.factoryAh)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ factory Ah: %w", err)
+	}
+	battery.FactoryAh = float64(factoryAh) / 100
+
+	cellTemp, err := d.ReadU16(layout.cellTemperature)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ cell temperature: %w", err)
+	}
+	battery.CellTemperature = int(cellTemp)
+
+	mosfetTemp, err := d.ReadU16(layout.mosfetTemperature)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ MOSFET temperature: %w", err)
+	}
+	battery.MosfetTemperature = int(mosfetTemp)
+
+	heat, err := d.ReadBytes(layout.heat, 4)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ heat status: %w", err)
+	}
+	battery.Heat = hex.EncodeToString(heat)
+	if heat[0]>>7 >= 8 {
+		battery.DischargeSwitchState = 0
+	} else {
+		battery.DischargeSwitchState = 1
+	}
+
+	protectState, err := d.ReadBytes(layout.protectState, 4)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ protect state: %w", err)
+	}
+	battery.ProtectState = hex.EncodeToString(protectState)
+
+	failureState, err := d.ReadBytes(layout.failureState, 4)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ failure state: %w", err)
+	}
+	battery.FailureState = append([]byte(nil), failureState...)
+
+	equilibriumState, err := d.ReadU32(layout.equilibriumState)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ equilibrium state: %w", err)
+	}
+	battery.EquilibriumState = int(equilibriumState)
+
+	batteryState, err := d.ReadU16(layout.batteryState)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ battery state: %w", err)
+	}
+	battery.BatteryState = int(batteryState)
+
+	soc, err := d.ReadU16(layout.soc)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ SOC: %w", err)
+	}
+	battery.SOC = int(soc)
+
+	soh, err := d.ReadU32(layout.soh)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ SOH: %w", err)
+	}
+	battery.SOH = int(soh)
+
+	dischargesCount, err := d.ReadU32(layout.dischargesCount)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ discharges count: %w", err)
+	}
+	battery.DischargesCount = int(dischargesCount)
+
+	dischargesAHCount, err := d.ReadU32(layout.dischargesAHCount)
+	if err != nil {
+		return nil, fmt.Errorf("driver: PQ discharges Ah count: %w", err)
+	}
+	battery.DischargesAHCount = int(dischargesAHCount)
+
+	battery.BatteryStatus = getBatteryStatus(battery)
+
+	if battery.EquilibriumState > 0 {
+		battery.BalanceStatus = "Battery cells are being balanced for better performance."
+	} else {
+		battery.BalanceStatus = "All cells are well-balanced."
+	}
+
+	if battery.FailureState[0] > 0 || battery.FailureState[1] > 0 {
+		battery.CellStatus = "Fault alert! There may be a problem with cell."
+	} else {
+		battery.CellStatus = "Battery is in optimal working condition."
+	}
+
+	if battery.Heat[7] == '2' {
+		battery.HeatStatus = "Self-heating is on"
+	} else {
+		battery.HeatStatus = "Self-heating is off"
+	}
+
+	return battery, nil
+}
+
+func getBatteryStatus(battery *bms.BatteryInfo) string {
+	var status string
+	if battery.Current == 0 {
+		status = "Standby"
+	} else if battery.Current > 0 {
+		status = "Charging"
+	} else if battery.Current < 0 {
+		status = "Discharging"
+	}
+
+	if battery.SOC >= 100 || battery.BatteryState == 4 {
+		status = "Full Charge"
+	}
+
+	return status
+}
+
+// pqVersion is the decoded GET_VERSION response.
+type pqVersion struct {
+	Firmware        string
+	ManufactureDate string
+	Hardware        string
+}
+
+// parseVersion decodes a GET_VERSION response payload (header and checksum
+// already stripped by the framer), bounds-checking every field instead of
+// slicing a fixed-size tail it cannot validate.
+func parseVersion(payload []byte) (pqVersion, error) {
+	d := NewDecoder(payload)
+
+	major, err := d.ReadU16(0)
+	if err != nil {
+		return pqVersion{}, fmt.Errorf("driver: PQ firmware major version: %w", err)
+	}
+	minor, err := d.ReadU16(2)
+	if err != nil {
+		return pqVersion{}, fmt.Errorf("driver: PQ firmware minor version: %w", err)
+	}
+	patch, err := d.ReadU16(4)
+	if err != nil {
+		return pqVersion{}, fmt.Errorf("driver: PQ firmware patch version: %w", err)
+	}
+
+	year, err := d.ReadU16(6)
+	if err != nil {
+		return pqVersion{}, fmt.Errorf("driver: PQ manufacture year: %w", err)
+	}
+	dateTail, err := d.ReadBytes(8, 2)
+	if err != nil {
+		return pqVersion{}, fmt.Errorf("driver: PQ manufacture month/day: %w", err)
+	}
+
+	var hardwareVersion strings.Builder
+	for i := 0; i < len(payload); i += 2 {
+		ver := payload[i]
+		if ver >= 32 && ver <= 126 {
+			hardwareVersion.WriteByte(ver)
+		}
+	}
+
+	return pqVersion{
+		Firmware:        fmt.Sprintf("%d.%d.%d", major, minor, patch),
+		ManufactureDate: fmt.Sprintf("%d-%d-%d", year, dateTail[0], dateTail[1]),
+		Hardware:        hardwareVersion.String(),
+	}, nil
+}
@@ -0,0 +1,132 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"conductor/bms"
+)
+
+// PQFramer reassembles PQ-protocol BLE notification fragments into
+// complete, checksum-verified frames.
+//
+// Frame layout: 00 00 04 01 <cmd> 55 AA <len> <len bytes of payload> <checksum>
+// where checksum is the low byte of the sum of the payload bytes.
+type PQFramer struct {
+	mu      sync.Mutex
+	buf     []byte
+	onFrame func(bms.Frame)
+	waiters map[byte][]chan []byte
+}
+
+// NewPQFramer returns a PQFramer that invokes onFrame (if non-nil) for
+// every frame it successfully reassembles and validates.
+func NewPQFramer(onFrame func(bms.Frame)) *PQFramer {
+	return &PQFramer{
+		onFrame: onFrame,
+		waiters: make(map[byte][]chan []byte),
+	}
+}
+
+// Feed implements bms.Framer. Malformed or interleaved data is
+// resynchronized past a byte at a time rather than handed to callers.
+func (f *PQFramer) Feed(data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.buf = append(f.buf, data...)
+	for {
+		frame, consumed, ok := extractPQFrame(f.buf)
+		if !ok {
+			if consumed > 0 {
+				f.buf = f.buf[consumed:]
+			}
+			return
+		}
+		f.buf = f.buf[consumed:]
+		f.deliver(frame)
+	}
+}
+
+// deliver must be called with f.mu held.
+func (f *PQFramer) deliver(frame bms.Frame) {
+	for _, ch := range f.waiters[frame.Command] {
+		ch <- frame.Payload
+	}
+	delete(f.waiters, frame.Command)
+
+	if f.onFrame != nil {
+		f.onFrame(frame)
+	}
+}
+
+// WaitForCommand implements bms.Framer.
+func (f *PQFramer) WaitForCommand(ctx context.Context, cmd byte) ([]byte, error) {
+	ch := make(chan []byte, 1)
+
+	f.mu.Lock()
+	f.waiters[cmd] = append(f.waiters[cmd], ch)
+	f.mu.Unlock()
+
+	select {
+	case payload := <-ch:
+		return payload, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("driver: waiting for PQ response to command 0x%02x: %w", cmd, ctx.Err())
+	}
+}
+
+const pqFrameHeaderLen = 8 // 00 00 04 01 <cmd> 55 AA <len>
+
+// extractPQFrame looks for one complete, checksum-valid PQ frame at the
+// start of buf. It returns (frame, consumed, true) when it finds one, or
+// (Frame{}, consumed, false) when buf doesn't yet contain a full frame --
+// consumed bytes should be dropped (as unrecoverable header noise) before
+// the caller tries again on the next Feed.
+func extractPQFrame(buf []byte) (bms.Frame, int, bool) {
+	for i := 0; i+pqFrameHeaderLen <= len(buf); i++ {
+		if buf[i] != 0x00 || buf[i+1] != 0x00 || buf[i+2] != 0x04 || buf[i+3] != 0x01 {
+			continue
+		}
+		if buf[i+5] != 0x55 || buf[i+6] != 0xAA {
+			continue
+		}
+
+		cmd := buf[i+4]
+		length := int(buf[i+7])
+		total := pqFrameHeaderLen + length + 1 // header + payload + checksum byte
+
+		if i+total > len(buf) {
+			// Header found but the frame isn't fully buffered yet; leave
+			// it in place and wait for more data.
+			return bms.Frame{}, i, false
+		}
+
+		payload := buf[i+pqFrameHeaderLen : i+pqFrameHeaderLen+length]
+		checksum := buf[i+total-1]
+		if checksum != sum8(payload) {
+			// Looks like a header but fails the checksum -- probably
+			// interleaved or corrupt. Skip past it and keep scanning.
+			continue
+		}
+
+		frame := bms.Frame{Command: cmd, Payload: append([]byte(nil), payload...)}
+		return frame, i + total, true
+	}
+
+	// No header found at all; keep at most pqFrameHeaderLen-1 trailing
+	// bytes in case a header is split across Feed calls.
+	if len(buf) > pqFrameHeaderLen {
+		return bms.Frame{}, len(buf) - pqFrameHeaderLen + 1, false
+	}
+	return bms.Frame{}, 0, false
+}
+
+func sum8(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
@@ -0,0 +1,89 @@
+package driver
+
+import "testing"
+
+// validPQFrame builds a well-formed PQ frame for cmd carrying payload, with
+// a correct trailing checksum.
+func validPQFrame(cmd byte, payload []byte) []byte {
+	frame := []byte{0x00, 0x00, 0x04, 0x01, cmd, 0x55, 0xAA, byte(len(payload))}
+	frame = append(frame, payload...)
+	return append(frame, sum8(payload))
+}
+
+func TestExtractPQFrameValid(t *testing.T) {
+	payload := []byte{0x10, 0x20, 0x30}
+	buf := validPQFrame(0x13, payload)
+
+	frame, consumed, ok := extractPQFrame(buf)
+	if !ok {
+		t.Fatal("extractPQFrame: got ok=false on a well-formed frame")
+	}
+	if consumed != len(buf) {
+		t.Errorf("consumed = %d, want %d", consumed, len(buf))
+	}
+	if frame.Command != 0x13 {
+		t.Errorf("Command = %#02x, want 0x13", frame.Command)
+	}
+	if string(frame.Payload) != string(payload) {
+		t.Errorf("Payload = %v, want %v", frame.Payload, payload)
+	}
+}
+
+func TestExtractPQFrameIncomplete(t *testing.T) {
+	buf := validPQFrame(0x13, []byte{0x10, 0x20, 0x30})
+	_, _, ok := extractPQFrame(buf[:len(buf)-2])
+	if ok {
+		t.Fatal("extractPQFrame: got ok=true on a truncated frame")
+	}
+}
+
+func TestExtractPQFrameBadChecksum(t *testing.T) {
+	buf := validPQFrame(0x13, []byte{0x10, 0x20, 0x30})
+	buf[len(buf)-1] ^= 0xFF // corrupt the checksum byte
+
+	_, _, ok := extractPQFrame(buf)
+	if ok {
+		t.Fatal("extractPQFrame: got ok=true on a frame with a bad checksum")
+	}
+}
+
+func TestExtractPQFrameGarbagePrefix(t *testing.T) {
+	// Noise ahead of a valid frame (e.g. another vendor's notification
+	// interleaved on the same characteristic) should be skipped rather
+	// than misread as part of the frame.
+	payload := []byte{0xAA, 0xBB}
+	valid := validPQFrame(0x16, payload)
+	buf := append([]byte{0xDE, 0xAD, 0xBE, 0xEF, 0x00, 0x00, 0x04}, valid...)
+
+	frame, consumed, ok := extractPQFrame(buf)
+	if !ok {
+		t.Fatal("extractPQFrame: got ok=false with garbage prefixed to a valid frame")
+	}
+	if consumed != len(buf) {
+		t.Errorf("consumed = %d, want %d", consumed, len(buf))
+	}
+	if frame.Command != 0x16 {
+		t.Errorf("Command = %#02x, want 0x16", frame.Command)
+	}
+}
+
+func TestExtractPQFrameAllGarbage(t *testing.T) {
+	buf := []byte{0xDE, 0xAD, 0xBE, 0xEF, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	frame, consumed, ok := extractPQFrame(buf)
+	if ok {
+		t.Fatalf("extractPQFrame: got ok=true on pure garbage, frame = %+v", frame)
+	}
+	if consumed < 0 || consumed > len(buf) {
+		t.Errorf("consumed = %d out of bounds for a %d-byte buffer", consumed, len(buf))
+	}
+}
+
+func TestExtractPQFrameEmpty(t *testing.T) {
+	_, consumed, ok := extractPQFrame(nil)
+	if ok {
+		t.Fatal("extractPQFrame: got ok=true on an empty buffer")
+	}
+	if consumed != 0 {
+		t.Errorf("consumed = %d, want 0 for an empty buffer", consumed)
+	}
+}
@@ -0,0 +1,74 @@
+package driver
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseBatteryInfoTruncatedFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int // length of garbage payload fed in
+	}{
+		{"empty", 0},
+		{"just the pack voltage", 4},
+		{"missing trailing fields", 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := bytes.Repeat([]byte{0xAB}, tt.n)
+			info, err := parseBatteryInfo(payload, defaultBatteryInfoLayout)
+			if err == nil {
+				t.Fatalf("parseBatteryInfo(%d garbage bytes): got nil error, want error", tt.n)
+			}
+			if info != nil {
+				t.Errorf("parseBatteryInfo(%d garbage bytes): got non-nil info on error", tt.n)
+			}
+		})
+	}
+}
+
+func TestParseBatteryInfoFullLengthGarbage(t *testing.T) {
+	// A full-length payload of garbage bytes should still decode without
+	// panicking, even though the resulting values are meaningless.
+	payload := bytes.Repeat([]byte{0xFF}, 96)
+	info, err := parseBatteryInfo(payload, defaultBatteryInfoLayout)
+	if err != nil {
+		t.Fatalf("parseBatteryInfo(96 garbage bytes): unexpected error: %v", err)
+	}
+	if info == nil {
+		t.Fatal("parseBatteryInfo(96 garbage bytes): got nil info with nil error")
+	}
+}
+
+func TestParseVersionTruncatedFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+	}{
+		{"empty", 0},
+		{"just firmware major", 2},
+		{"missing manufacture month/day", 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := bytes.Repeat([]byte{0x00}, tt.n)
+			version, err := parseVersion(payload)
+			if err == nil {
+				t.Fatalf("parseVersion(%d garbage bytes): got nil error, want error", tt.n)
+			}
+			if version != (pqVersion{}) {
+				t.Errorf("parseVersion(%d garbage bytes): got non-zero version on error: %+v", tt.n, version)
+			}
+		})
+	}
+}
+
+func TestParseVersionFullLengthGarbage(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x41}, 10)
+	if _, err := parseVersion(payload); err != nil {
+		t.Fatalf("parseVersion(10 garbage bytes): unexpected error: %v", err)
+	}
+}
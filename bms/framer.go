@@ -0,0 +1,32 @@
+package bms
+
+import "context"
+
+// Frame is one reassembled response frame, tagged with the command-id byte
+// it responds to (lifted from whatever fixed offset the vendor protocol
+// carries it at).
+type Frame struct {
+	Command byte
+	Payload []byte
+}
+
+// Framer reassembles BLE notification fragments into complete, validated
+// response frames. BLE notifications are capped at ATT_MTU (typically 20
+// bytes), so a single response is usually delivered across several
+// notifications; a Framer buffers them until it recognizes a full frame in
+// whatever header/footer/checksum shape its vendor protocol uses.
+//
+// Frame layout and checksum validation are vendor-specific, so each Driver
+// provides its own Framer implementation via Driver.NewFramer rather than
+// conductor assuming one fixed wire format for every BMS family.
+type Framer interface {
+	// Feed appends a notification payload to the framer's internal buffer
+	// and delivers any complete frames it can extract to the callback
+	// passed to the Framer's constructor and to any goroutine blocked in
+	// WaitForCommand for that frame's command.
+	Feed(data []byte)
+
+	// WaitForCommand blocks until a frame for cmd arrives or ctx is
+	// cancelled.
+	WaitForCommand(ctx context.Context, cmd byte) ([]byte, error)
+}
@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"tinygo.org/x/bluetooth"
+
+	"conductor/bms"
+	"conductor/supervisor"
+)
+
+// muninFields lists the BatteryInfo values the Munin plugin graphs, in the
+// order they're printed. Following the munin-miflora convention, one
+// plugin instance graphs one pack.
+var muninFields = []struct {
+	name  string
+	label string
+	value func(*bms.BatteryInfo) float64
+}{
+	{"pack_voltage", "Pack voltage (V)", func(b *bms.BatteryInfo) float64 { return float64(b.PackVoltage) / 1000 }},
+	{"current", "Current (A)", func(b *bms.BatteryInfo) float64 { return b.Current }},
+	{"soc", "State of charge (%)", func(b *bms.BatteryInfo) float64 { return float64(b.SOC) }},
+	{"soh", "State of health (%)", func(b *bms.BatteryInfo) float64 { return float64(b.SOH) }},
+	{"cell_temperature", "Cell temperature (C)", func(b *bms.BatteryInfo) float64 { return float64(b.CellTemperature) }},
+	{"mosfet_temperature", "MOSFET temperature (C)", func(b *bms.BatteryInfo) float64 { return float64(b.MosfetTemperature) }},
+	{"remaining_ah", "Remaining capacity (Ah)", func(b *bms.BatteryInfo) float64 { return b.RemainAh }},
+	{"discharge_cycles", "Discharge cycles", func(b *bms.BatteryInfo) float64 { return float64(b.DischargesCount) }},
+}
+
+// PrintMuninConfig writes the "conductor munin config" output: the graph
+// metadata and per-field declarations Munin needs before it will call
+// fetch.
+func PrintMuninConfig(w io.Writer) {
+	fmt.Fprintln(w, "graph_title BMS pack telemetry")
+	fmt.Fprintln(w, "graph_vlabel value")
+	fmt.Fprintln(w, "graph_category sensors")
+	for _, field := range muninFields {
+		fmt.Fprintf(w, "%s.label %s\n", field.name, field.label)
+		fmt.Fprintf(w, "%s.type GAUGE\n", field.name)
+	}
+}
+
+// PrintMuninValues writes the "conductor munin fetch" output: one
+// field.value line per configured field, read off a single BatteryInfo
+// sample.
+func PrintMuninValues(w io.Writer, info *bms.BatteryInfo) {
+	for _, field := range muninFields {
+		fmt.Fprintf(w, "%s.value %v\n", field.name, field.value(info))
+	}
+}
+
+// FetchOnce takes a single sample from target, for use by the Munin fetch
+// mode which is invoked fresh by munin-node on every run rather than
+// staying resident like the supervisor-driven exporters.
+func FetchOnce(ctx context.Context, adapter *bluetooth.Adapter, target string) (*bms.BatteryInfo, error) {
+	sup := supervisor.New(adapter, supervisor.Config{
+		Targets:      []string{target},
+		PollInterval: time.Hour, // irrelevant: we stop after the first sample
+	})
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go sup.Run(runCtx)
+
+	for {
+		select {
+		case sample := <-sup.Samples():
+			if sample.Err != nil {
+				continue
+			}
+			if info, ok := sample.Info.(*bms.BatteryInfo); ok {
+				return info, nil
+			}
+		case <-ctx.Done():
+			return nil, fmt.Errorf("exporter: no sample from %s: %w", target, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"conductor/bms"
+)
+
+func TestMuninFieldValues(t *testing.T) {
+	// PackVoltage and Voltage are parsed from different payload offsets
+	// (see bms/driver/pq.go); pack_voltage must read PackVoltage.
+	info := &bms.BatteryInfo{
+		PackVoltage:       52100,
+		Voltage:           51800,
+		Current:           1.5,
+		SOC:               87,
+		SOH:               99,
+		CellTemperature:   24,
+		MosfetTemperature: 30,
+		RemainAh:          45.6,
+		DischargesCount:   12,
+	}
+
+	want := map[string]float64{
+		"pack_voltage":       52.1,
+		"current":            1.5,
+		"soc":                87,
+		"soh":                99,
+		"cell_temperature":   24,
+		"mosfet_temperature": 30,
+		"remaining_ah":       45.6,
+		"discharge_cycles":   12,
+	}
+
+	for _, field := range muninFields {
+		got := field.value(info)
+		w, ok := want[field.name]
+		if !ok {
+			t.Fatalf("unexpected field %q in muninFields; add it to this test's want map", field.name)
+		}
+		if got != w {
+			t.Errorf("field %q = %v, want %v", field.name, got, w)
+		}
+	}
+}
+
+func TestPrintMuninValues(t *testing.T) {
+	info := &bms.BatteryInfo{PackVoltage: 52100}
+
+	var buf bytes.Buffer
+	PrintMuninValues(&buf, info)
+
+	if !strings.Contains(buf.String(), "pack_voltage.value 52.1\n") {
+		t.Errorf("PrintMuninValues output missing expected pack_voltage line, got:\n%s", buf.String())
+	}
+}
+
+func TestPrintMuninConfigListsEveryField(t *testing.T) {
+	var buf bytes.Buffer
+	PrintMuninConfig(&buf)
+
+	out := buf.String()
+	for _, field := range muninFields {
+		if !strings.Contains(out, field.name+".label "+field.label) {
+			t.Errorf("PrintMuninConfig output missing label line for %q", field.name)
+		}
+		if !strings.Contains(out, field.name+".type GAUGE") {
+			t.Errorf("PrintMuninConfig output missing type line for %q", field.name)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package exporter turns parsed BMS telemetry into formats existing
+// monitoring stacks already know how to scrape: a Prometheus /metrics
+// endpoint and a classic Munin plugin.
+package exporter
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"conductor/bms"
+	"conductor/supervisor"
+)
+
+// PrometheusExporter maintains a set of gauges driven by a stream of
+// supervisor.Sample values and serves them over HTTP.
+type PrometheusExporter struct {
+	packVoltage     *prometheus.GaugeVec
+	cellVoltage     *prometheus.GaugeVec
+	current         *prometheus.GaugeVec
+	soc             *prometheus.GaugeVec
+	soh             *prometheus.GaugeVec
+	cellTemp        *prometheus.GaugeVec
+	mosfetTemp      *prometheus.GaugeVec
+	remainingAh     *prometheus.GaugeVec
+	dischargeCycles *prometheus.GaugeVec
+	batteryStatus   *prometheus.GaugeVec
+	cellStatus      *prometheus.GaugeVec
+	heatStatus      *prometheus.GaugeVec
+
+	lastBatteryStatus map[string]string
+	lastCellStatus    map[string]string
+	lastHeatStatus    map[string]string
+}
+
+// NewPrometheusExporter registers and returns a PrometheusExporter against
+// the default Prometheus registry.
+func NewPrometheusExporter() *PrometheusExporter {
+	return &PrometheusExporter{
+		packVoltage: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_pack_voltage_volts",
+			Help: "Pack voltage in volts.",
+		}, []string{"address"}),
+		cellVoltage: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_cell_voltage_volts",
+			Help: "Per-cell voltage in volts.",
+		}, []string{"address", "cell"}),
+		current: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_current_amperes",
+			Help: "Pack current in amperes; positive is charging, negative is discharging.",
+		}, []string{"address"}),
+		soc: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_soc_percent",
+			Help: "State of charge as a percentage.",
+		}, []string{"address"}),
+		soh: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_soh_percent",
+			Help: "State of health as a percentage.",
+		}, []string{"address"}),
+		cellTemp: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_cell_temperature_celsius",
+			Help: "Cell temperature in degrees Celsius.",
+		}, []string{"address"}),
+		mosfetTemp: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_mosfet_temperature_celsius",
+			Help: "MOSFET temperature in degrees Celsius.",
+		}, []string{"address"}),
+		remainingAh: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_remaining_ah",
+			Help: "Remaining capacity in amp-hours.",
+		}, []string{"address"}),
+		dischargeCycles: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_discharge_cycles_total",
+			Help: "Number of discharge cycles reported by the BMS.",
+		}, []string{"address"}),
+		batteryStatus: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_battery_status",
+			Help: "Always 1, labelled with the battery_status string currently reported by the BMS; the series for a previous status is removed.",
+		}, []string{"address", "battery_status"}),
+		cellStatus: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_cell_status",
+			Help: "Always 1, labelled with the cell_status string currently reported by the BMS; the series for a previous status is removed.",
+		}, []string{"address", "cell_status"}),
+		heatStatus: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bms_heat_status",
+			Help: "Always 1, labelled with the heat_status string currently reported by the BMS; the series for a previous status is removed.",
+		}, []string{"address", "heat_status"}),
+		lastBatteryStatus: make(map[string]string),
+		lastCellStatus:    make(map[string]string),
+		lastHeatStatus:    make(map[string]string),
+	}
+}
+
+// Handler returns the http.Handler that serves the registered metrics; wire
+// it up under /metrics.
+func (e *PrometheusExporter) Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Consume reads samples until the channel is closed, updating gauges for
+// every successfully parsed *bms.BatteryInfo and skipping the rest.
+func (e *PrometheusExporter) Consume(samples <-chan supervisor.Sample) {
+	for sample := range samples {
+		if sample.Err != nil {
+			continue
+		}
+		info, ok := sample.Info.(*bms.BatteryInfo)
+		if !ok {
+			continue
+		}
+		e.observe(sample.Address, info)
+	}
+}
+
+func (e *PrometheusExporter) observe(address string, info *bms.BatteryInfo) {
+	e.packVoltage.WithLabelValues(address).Set(float64(info.PackVoltage) / 1000)
+	for cell, voltage := range info.BatteryPack {
+		e.cellVoltage.WithLabelValues(address, strconv.Itoa(cell)).Set(voltage)
+	}
+	e.current.WithLabelValues(address).Set(info.Current)
+	e.soc.WithLabelValues(address).Set(float64(info.SOC))
+	e.soh.WithLabelValues(address).Set(float64(info.SOH))
+	e.cellTemp.WithLabelValues(address).Set(float64(info.CellTemperature))
+	e.mosfetTemp.WithLabelValues(address).Set(float64(info.MosfetTemperature))
+	e.remainingAh.WithLabelValues(address).Set(info.RemainAh)
+	e.dischargeCycles.WithLabelValues(address).Set(float64(info.DischargesCount))
+
+	setState(e.batteryStatus, e.lastBatteryStatus, address, info.BatteryStatus)
+	setState(e.cellStatus, e.lastCellStatus, address, info.CellStatus)
+	setState(e.heatStatus, e.lastHeatStatus, address, info.HeatStatus)
+}
+
+// setState implements the "only one label value is 1" pattern for a status
+// string: it clears the previously reported state for address (if any)
+// before setting the current one.
+func setState(vec *prometheus.GaugeVec, last map[string]string, address, state string) {
+	if prev, ok := last[address]; ok && prev != state {
+		vec.DeleteLabelValues(address, prev)
+	}
+	vec.WithLabelValues(address, state).Set(1)
+	last[address] = state
+}
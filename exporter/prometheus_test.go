@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"conductor/bms"
+)
+
+// newTestExporter builds a PrometheusExporter the same way
+// NewPrometheusExporter does, but with unregistered gauges so tests don't
+// collide with each other (or a real exporter) on the default registry.
+func newTestExporter() *PrometheusExporter {
+	return &PrometheusExporter{
+		packVoltage:       prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_pack_voltage_volts"}, []string{"address"}),
+		cellVoltage:       prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_cell_voltage_volts"}, []string{"address", "cell"}),
+		current:           prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_current_amperes"}, []string{"address"}),
+		soc:               prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_soc_percent"}, []string{"address"}),
+		soh:               prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_soh_percent"}, []string{"address"}),
+		cellTemp:          prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_cell_temperature_celsius"}, []string{"address"}),
+		mosfetTemp:        prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_mosfet_temperature_celsius"}, []string{"address"}),
+		remainingAh:       prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_remaining_ah"}, []string{"address"}),
+		dischargeCycles:   prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_discharge_cycles_total"}, []string{"address"}),
+		batteryStatus:     prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_battery_status"}, []string{"address", "battery_status"}),
+		cellStatus:        prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_cell_status"}, []string{"address", "cell_status"}),
+		heatStatus:        prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "bms_heat_status"}, []string{"address", "heat_status"}),
+		lastBatteryStatus: make(map[string]string),
+		lastCellStatus:    make(map[string]string),
+		lastHeatStatus:    make(map[string]string),
+	}
+}
+
+func TestObservePackVoltage(t *testing.T) {
+	// PackVoltage and Voltage are parsed from different payload offsets
+	// (see bms/driver/pq.go); the pack-voltage metric must read
+	// PackVoltage, not Voltage.
+	info := &bms.BatteryInfo{
+		PackVoltage: 52100,
+		Voltage:     51800,
+	}
+
+	e := newTestExporter()
+	e.observe("AA:BB:CC:DD:EE:FF", info)
+
+	got := testutil.ToFloat64(e.packVoltage.WithLabelValues("AA:BB:CC:DD:EE:FF"))
+	if want := 52.1; got != want {
+		t.Errorf("bms_pack_voltage_volts = %v, want %v", got, want)
+	}
+}
+
+func TestObserveCellVoltages(t *testing.T) {
+	info := &bms.BatteryInfo{
+		BatteryPack: map[int]float64{1: 3.3, 2: 3.31},
+	}
+
+	e := newTestExporter()
+	e.observe("AA:BB:CC:DD:EE:FF", info)
+
+	if got := testutil.ToFloat64(e.cellVoltage.WithLabelValues("AA:BB:CC:DD:EE:FF", "1")); got != 3.3 {
+		t.Errorf("cell 1 voltage = %v, want 3.3", got)
+	}
+	if got := testutil.ToFloat64(e.cellVoltage.WithLabelValues("AA:BB:CC:DD:EE:FF", "2")); got != 3.31 {
+		t.Errorf("cell 2 voltage = %v, want 3.31", got)
+	}
+}
+
+func TestSetStateClearsPreviousValue(t *testing.T) {
+	e := newTestExporter()
+
+	e.observe("addr", &bms.BatteryInfo{BatteryStatus: "Charging"})
+	if got := testutil.ToFloat64(e.batteryStatus.WithLabelValues("addr", "Charging")); got != 1 {
+		t.Fatalf("battery_status{Charging} = %v, want 1", got)
+	}
+
+	e.observe("addr", &bms.BatteryInfo{BatteryStatus: "Standby"})
+	if got := testutil.ToFloat64(e.batteryStatus.WithLabelValues("addr", "Standby")); got != 1 {
+		t.Errorf("battery_status{Standby} = %v, want 1", got)
+	}
+
+	// The previous "Charging" series should have been deleted outright, not
+	// just left at 0, so a stale status can't linger in a query result.
+	if n := testutil.CollectAndCount(e.batteryStatus); n != 1 {
+		t.Errorf("bms_battery_status has %d series after a status transition, want 1", n)
+	}
+}
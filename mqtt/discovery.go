@@ -0,0 +1,121 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// haSensor describes one Home Assistant MQTT discovery sensor derived from
+// a fixed field of bms.BatteryInfo.
+type haSensor struct {
+	key           string
+	name          string
+	unit          string
+	valueTemplate string
+	deviceClass   string
+}
+
+// haSensors covers the cells, SOC/SOH, currents, temperatures and derived
+// status strings called out in BatteryInfo; per-cell sensors are announced
+// separately since the cell count isn't known up front.
+var haSensors = []haSensor{
+	{"pack_voltage", "Pack Voltage", "V", `{{ (value_json.PackVoltage | float) / 1000 }}`, "voltage"},
+	{"current", "Current", "A", `{{ value_json.Current }}`, "current"},
+	{"soc", "State of Charge", "%", `{{ value_json.SOC }}`, "battery"},
+	{"soh", "State of Health", "%", `{{ value_json.SOH }}`, ""},
+	{"cell_temperature", "Cell Temperature", "°C", `{{ value_json.CellTemperature }}`, "temperature"},
+	{"mosfet_temperature", "MOSFET Temperature", "°C", `{{ value_json.MosfetTemperature }}`, "temperature"},
+	{"remaining_ah", "Remaining Capacity", "Ah", `{{ value_json.RemainAh }}`, ""},
+	{"discharge_cycles", "Discharge Cycles", "", `{{ value_json.DischargesCount }}`, ""},
+	{"battery_status", "Battery Status", "", `{{ value_json.BatteryStatus }}`, ""},
+	{"cell_status", "Cell Status", "", `{{ value_json.CellStatus }}`, ""},
+	{"heat_status", "Heat Status", "", `{{ value_json.HeatStatus }}`, ""},
+}
+
+// haDiscoveryConfig is the subset of the Home Assistant MQTT discovery
+// sensor schema conductor needs.
+type haDiscoveryConfig struct {
+	Name              string   `json:"name"`
+	StateTopic        string   `json:"state_topic"`
+	UnitOfMeasurement string   `json:"unit_of_measurement,omitempty"`
+	ValueTemplate     string   `json:"value_template"`
+	UniqueID          string   `json:"unique_id"`
+	DeviceClass       string   `json:"device_class,omitempty"`
+	Device            haDevice `json:"device"`
+}
+
+type haDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+// announceDevice publishes retained discovery configs for every fixed
+// sensor in haSensors for address.
+func (p *Publisher) announceDevice(address string) {
+	device := p.haDevice(address)
+	for _, sensor := range haSensors {
+		cfg := haDiscoveryConfig{
+			Name:              sensor.name,
+			StateTopic:        p.stateTopic(address),
+			UnitOfMeasurement: sensor.unit,
+			ValueTemplate:     sensor.valueTemplate,
+			UniqueID:          fmt.Sprintf("conductor_%s_%s", sanitizeMAC(address), sensor.key),
+			DeviceClass:       sensor.deviceClass,
+			Device:            device,
+		}
+		p.publishDiscoveryConfig(sensor.key, address, cfg)
+	}
+}
+
+// announceCells publishes a discovery config for any cell index in pack
+// that hasn't been announced for address yet.
+func (p *Publisher) announceCells(address string, pack map[int]float64) {
+	seen, ok := p.announcedCell[address]
+	if !ok {
+		seen = make(map[int]bool)
+		p.announcedCell[address] = seen
+	}
+
+	device := p.haDevice(address)
+	for cell := range pack {
+		if seen[cell] {
+			continue
+		}
+		key := fmt.Sprintf("cell_%d_voltage", cell)
+		cfg := haDiscoveryConfig{
+			Name:              fmt.Sprintf("Cell %d Voltage", cell),
+			StateTopic:        p.stateTopic(address),
+			UnitOfMeasurement: "V",
+			ValueTemplate:     fmt.Sprintf(`{{ value_json.BatteryPack["%d"] }}`, cell),
+			UniqueID:          fmt.Sprintf("conductor_%s_%s", sanitizeMAC(address), key),
+			DeviceClass:       "voltage",
+			Device:            device,
+		}
+		p.publishDiscoveryConfig(key, address, cfg)
+		seen[cell] = true
+	}
+}
+
+func (p *Publisher) haDevice(address string) haDevice {
+	return haDevice{
+		Identifiers:  []string{sanitizeMAC(address)},
+		Name:         fmt.Sprintf("BMS %s", address),
+		Manufacturer: "conductor",
+	}
+}
+
+func (p *Publisher) publishDiscoveryConfig(key, address string, cfg haDiscoveryConfig) {
+	payload, err := json.Marshal(cfg)
+	if err != nil {
+		log.Printf("mqtt: marshalling discovery config for %s %s: %v", address, key, err)
+		return
+	}
+
+	topic := fmt.Sprintf("%s/sensor/%s/%s/config", p.cfg.DiscoveryPrefix, sanitizeMAC(address), key)
+	token := p.client.Publish(topic, p.cfg.QoS, true, payload)
+	if err := waitPublish(token); err != nil {
+		log.Printf("mqtt: publishing discovery config to %s: %v", topic, err)
+	}
+}
@@ -0,0 +1,139 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// fakeToken is a Token that's always already complete and error-free.
+type fakeToken struct{}
+
+func (fakeToken) Wait() bool                     { return true }
+func (fakeToken) WaitTimeout(time.Duration) bool { return true }
+func (fakeToken) Done() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+func (fakeToken) Error() error { return nil }
+
+// fakePublish records one Publish call's arguments.
+type fakePublish struct {
+	topic    string
+	payload  []byte
+	retained bool
+}
+
+// fakeMQTTClient is a paho.Client that records every Publish call instead
+// of touching the network, so discovery-config construction can be tested
+// without a broker.
+type fakeMQTTClient struct {
+	published []fakePublish
+}
+
+func (c *fakeMQTTClient) IsConnected() bool       { return true }
+func (c *fakeMQTTClient) IsConnectionOpen() bool  { return true }
+func (c *fakeMQTTClient) Connect() paho.Token     { return fakeToken{} }
+func (c *fakeMQTTClient) Disconnect(quiesce uint) {}
+func (c *fakeMQTTClient) Publish(topic string, qos byte, retained bool, payload interface{}) paho.Token {
+	var b []byte
+	switch p := payload.(type) {
+	case []byte:
+		b = p
+	case string:
+		b = []byte(p)
+	}
+	c.published = append(c.published, fakePublish{topic: topic, payload: b, retained: retained})
+	return fakeToken{}
+}
+func (c *fakeMQTTClient) Subscribe(topic string, qos byte, callback paho.MessageHandler) paho.Token {
+	return fakeToken{}
+}
+func (c *fakeMQTTClient) SubscribeMultiple(filters map[string]byte, callback paho.MessageHandler) paho.Token {
+	return fakeToken{}
+}
+func (c *fakeMQTTClient) Unsubscribe(topics ...string) paho.Token             { return fakeToken{} }
+func (c *fakeMQTTClient) AddRoute(topic string, callback paho.MessageHandler) {}
+func (c *fakeMQTTClient) OptionsReader() paho.ClientOptionsReader {
+	return paho.ClientOptionsReader{}
+}
+
+func newTestPublisher() (*Publisher, *fakeMQTTClient) {
+	client := &fakeMQTTClient{}
+	p := &Publisher{
+		cfg: Config{
+			DiscoveryPrefix: "homeassistant",
+			TopicPrefix:     "conductor",
+		},
+		client:          client,
+		announcedDevice: make(map[string]bool),
+		announcedCell:   make(map[string]map[int]bool),
+	}
+	return p, client
+}
+
+func TestSanitizeMAC(t *testing.T) {
+	got := sanitizeMAC("AA:BB:CC:DD:EE:FF")
+	if want := "aabbccddeeff"; got != want {
+		t.Errorf("sanitizeMAC = %q, want %q", got, want)
+	}
+}
+
+func TestAnnounceDevicePackVoltageSensor(t *testing.T) {
+	p, client := newTestPublisher()
+	p.announceDevice("AA:BB:CC:DD:EE:FF")
+
+	var found bool
+	for _, pub := range client.published {
+		if pub.topic != "homeassistant/sensor/aabbccddeeff/pack_voltage/config" {
+			continue
+		}
+		found = true
+		var cfg haDiscoveryConfig
+		if err := json.Unmarshal(pub.payload, &cfg); err != nil {
+			t.Fatalf("unmarshalling pack_voltage discovery config: %v", err)
+		}
+		// Same bug as chunk0-4's Prometheus/Munin fields: this must read
+		// PackVoltage, not Voltage, or Home Assistant reports the wrong value.
+		if cfg.ValueTemplate != `{{ (value_json.PackVoltage | float) / 1000 }}` {
+			t.Errorf("pack_voltage value_template = %q, want a PackVoltage reference", cfg.ValueTemplate)
+		}
+	}
+	if !found {
+		t.Fatal("announceDevice never published a pack_voltage discovery config")
+	}
+}
+
+func TestAnnounceDevicePublishesEverySensor(t *testing.T) {
+	p, client := newTestPublisher()
+	p.announceDevice("AA:BB:CC:DD:EE:FF")
+
+	if got, want := len(client.published), len(haSensors); got != want {
+		t.Errorf("published %d discovery configs, want %d (one per haSensors entry)", got, want)
+	}
+}
+
+func TestAnnounceCellsSkipsAlreadyAnnounced(t *testing.T) {
+	p, client := newTestPublisher()
+
+	p.announceCells("AA:BB:CC:DD:EE:FF", map[int]float64{1: 3.3, 2: 3.31})
+	if got := len(client.published); got != 2 {
+		t.Fatalf("first announceCells published %d configs, want 2", got)
+	}
+
+	// Re-announcing the same cells (e.g. on the next poll) shouldn't
+	// re-publish discovery configs for cells already seen.
+	p.announceCells("AA:BB:CC:DD:EE:FF", map[int]float64{1: 3.29, 2: 3.30})
+	if got := len(client.published); got != 2 {
+		t.Errorf("after re-announcing known cells, published %d configs, want still 2", got)
+	}
+
+	// A newly-seen cell should still get its own discovery config.
+	p.announceCells("AA:BB:CC:DD:EE:FF", map[int]float64{1: 3.29, 2: 3.30, 3: 3.28})
+	if got := len(client.published); got != 3 {
+		t.Errorf("after announcing a new cell, published %d configs, want 3", got)
+	}
+}
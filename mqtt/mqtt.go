@@ -0,0 +1,159 @@
+// Package mqtt publishes parsed BMS telemetry to an MQTT broker as JSON,
+// with Home Assistant MQTT discovery so sensors show up without any
+// manual Home Assistant configuration.
+package mqtt
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"conductor/bms"
+	"conductor/supervisor"
+)
+
+// publishTimeout bounds how long a single Publish call waits for its broker
+// ack. Without it, a dead or slow broker would block Consume forever on
+// token.Wait(), and since Consume is fed from the supervisor's shared sample
+// channel, that stall would back up every other consumer and target too.
+const publishTimeout = 5 * time.Second
+
+// Config configures the broker connection and topic layout.
+type Config struct {
+	// Broker is the MQTT broker URL, e.g. "tcp://localhost:1883" or
+	// "ssl://broker.example:8883".
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+
+	// QoS applies to both state and discovery publishes. Retained applies
+	// to state publishes only; discovery configs are always retained.
+	QoS      byte
+	Retained bool
+
+	// TLSConfig is used as-is when set; required for ssl:// brokers that
+	// need custom CAs or client certs.
+	TLSConfig *tls.Config
+
+	// DiscoveryPrefix is the Home Assistant discovery topic prefix.
+	// Defaults to "homeassistant".
+	DiscoveryPrefix string
+
+	// TopicPrefix is the root of conductor's own state topics. Defaults
+	// to "conductor".
+	TopicPrefix string
+}
+
+// Publisher consumes a stream of supervisor.Sample and publishes each
+// parsed BatteryInfo to MQTT, announcing Home Assistant discovery configs
+// the first time it sees a device or a cell index.
+type Publisher struct {
+	cfg    Config
+	client paho.Client
+
+	announcedDevice map[string]bool
+	announcedCell   map[string]map[int]bool
+}
+
+// New builds a Publisher and its underlying MQTT client. Call Connect
+// before Consume.
+func New(cfg Config) *Publisher {
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = "homeassistant"
+	}
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = "conductor"
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetWill(cfg.TopicPrefix+"/status", "offline", cfg.QoS, true)
+	if cfg.Username != "" {
+		opts.SetUsername(cfg.Username)
+	}
+	if cfg.Password != "" {
+		opts.SetPassword(cfg.Password)
+	}
+	if cfg.TLSConfig != nil {
+		opts.SetTLSConfig(cfg.TLSConfig)
+	}
+
+	return &Publisher{
+		cfg:             cfg,
+		client:          paho.NewClient(opts),
+		announcedDevice: make(map[string]bool),
+		announcedCell:   make(map[string]map[int]bool),
+	}
+}
+
+// Connect dials the broker and publishes the "online" status retained
+// message that pairs with the LWT configured in New.
+func (p *Publisher) Connect() error {
+	token := p.client.Connect()
+	token.Wait()
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("mqtt: connecting to %s: %w", p.cfg.Broker, err)
+	}
+
+	p.client.Publish(p.cfg.TopicPrefix+"/status", p.cfg.QoS, true, "online")
+	return nil
+}
+
+// Consume reads samples until the channel is closed, publishing every
+// successfully parsed *bms.BatteryInfo and skipping the rest.
+func (p *Publisher) Consume(samples <-chan supervisor.Sample) {
+	for sample := range samples {
+		if sample.Err != nil {
+			continue
+		}
+		info, ok := sample.Info.(*bms.BatteryInfo)
+		if !ok {
+			continue
+		}
+		p.publish(sample.Address, info)
+	}
+}
+
+func (p *Publisher) publish(address string, info *bms.BatteryInfo) {
+	if !p.announcedDevice[address] {
+		p.announceDevice(address)
+		p.announcedDevice[address] = true
+	}
+	p.announceCells(address, info.BatteryPack)
+
+	payload, err := json.Marshal(info)
+	if err != nil {
+		log.Printf("mqtt: marshalling sample for %s: %v", address, err)
+		return
+	}
+
+	topic := p.stateTopic(address)
+	token := p.client.Publish(topic, p.cfg.QoS, p.cfg.Retained, payload)
+	if err := waitPublish(token); err != nil {
+		log.Printf("mqtt: publishing to %s: %v", topic, err)
+	}
+}
+
+// waitPublish waits up to publishTimeout for token to complete, returning an
+// error if the broker never acked in time or the publish itself failed.
+func waitPublish(token paho.Token) error {
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("no ack from broker after %s", publishTimeout)
+	}
+	return token.Error()
+}
+
+func (p *Publisher) stateTopic(address string) string {
+	return fmt.Sprintf("%s/%s/state", p.cfg.TopicPrefix, sanitizeMAC(address))
+}
+
+func sanitizeMAC(address string) string {
+	return strings.ToLower(strings.ReplaceAll(address, ":", ""))
+}
@@ -0,0 +1,265 @@
+// Package supervisor manages concurrent BLE connections to multiple BMS
+// units, polling each on its own goroutine and auto-reconnecting with
+// exponential backoff when a connection drops.
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"tinygo.org/x/bluetooth"
+
+	"conductor/bms"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	defaultResponseWait = 5 * time.Second
+	initialBackoff      = time.Second
+	maxBackoff          = time.Minute
+)
+
+// Config describes the set of BMS units a Supervisor should maintain
+// connections to.
+type Config struct {
+	// Targets is the list of devices to connect to, each either a BLE
+	// address (e.g. "AA:BB:CC:DD:EE:FF") or a substring to match against
+	// the device's advertised local name.
+	Targets []string
+
+	// PollInterval is how often each connected device is polled for a
+	// fresh sample. Defaults to 30s if zero.
+	PollInterval time.Duration
+}
+
+// Sample is one parsed reading from a BMS, tagged with the address of the
+// device it came from. Err is set instead of Info when a poll failed. After a
+// missing or unparsable response the supervisor keeps the connection open
+// and retries on the next tick; after a failed write it reconnects.
+type Sample struct {
+	Address string
+	Info    any
+	Err     error
+}
+
+// Supervisor maintains one goroutine per configured target, each of which
+// scans, connects, discovers the BMS characteristic, and polls on an
+// interval, reconnecting with exponential backoff on failure.
+type Supervisor struct {
+	adapter *bluetooth.Adapter
+	cfg     Config
+	samples chan Sample
+}
+
+// New returns a Supervisor for cfg using adapter. Run must be called to
+// start it.
+func New(adapter *bluetooth.Adapter, cfg Config) *Supervisor {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	return &Supervisor{
+		adapter: adapter,
+		cfg:     cfg,
+		samples: make(chan Sample, 16),
+	}
+}
+
+// Samples returns the channel every connected device's parsed readings are
+// published to.
+func (s *Supervisor) Samples() <-chan Sample {
+	return s.samples
+}
+
+// Run enables the adapter and blocks, supervising every configured target
+// until ctx is cancelled.
+func (s *Supervisor) Run(ctx context.Context) error {
+	if err := s.adapter.Enable(); err != nil {
+		return fmt.Errorf("supervisor: enabling adapter: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, target := range s.cfg.Targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			s.superviseTarget(ctx, target)
+		}(target)
+	}
+	wg.Wait()
+	return nil
+}
+
+// superviseTarget keeps target connected for as long as ctx is alive,
+// reconnecting with exponential backoff whenever connectAndPoll returns.
+func (s *Supervisor) superviseTarget(ctx context.Context, target string) {
+	backoff := initialBackoff
+	for ctx.Err() == nil {
+		if err := s.connectAndPoll(ctx, target); err != nil {
+			log.Printf("supervisor: %s: %v; retrying in %s", target, err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+		backoff = initialBackoff
+	}
+}
+
+func matchesTarget(target string, device bluetooth.ScanResult) bool {
+	if device.Address.String() == target {
+		return true
+	}
+	return target != "" && strings.Contains(device.LocalName(), target)
+}
+
+// connectAndPoll scans for target, connects once found, and polls it on
+// cfg.PollInterval until ctx is cancelled or the connection is lost.
+func (s *Supervisor) connectAndPoll(ctx context.Context, target string) error {
+	device, err := s.scanFor(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	driver := bms.Identify(device)
+	if driver == nil {
+		return fmt.Errorf("no driver recognizes %s [%s]", device.LocalName(), device.Address.String())
+	}
+	// Use a dedicated instance for this connection so per-connection state
+	// (like a learned firmware version) isn't shared across devices.
+	driver = driver.New()
+
+	dev, err := s.adapter.Connect(device.Address, bluetooth.ConnectionParams{})
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", target, err)
+	}
+	defer dev.Disconnect()
+
+	char, err := discoverCharacteristic(dev, driver)
+	if err != nil {
+		return fmt.Errorf("%s: %w", target, err)
+	}
+
+	framer := driver.NewFramer(nil)
+	if err := char.EnableNotifications(func(data []byte) { framer.Feed(data) }); err != nil {
+		return fmt.Errorf("enabling notifications on %s: %w", target, err)
+	}
+	defer char.EnableNotifications(nil)
+
+	address := device.Address.String()
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.pollOnce(ctx, address, driver, char, framer); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// scanFor blocks until a device matching target is found or ctx is
+// cancelled. adapter.Scan only returns once its callback calls StopScan, so
+// it's run on its own goroutine and raced against ctx.Done here rather than
+// called inline, where a target that never appears would block forever with
+// no way for ctx cancellation to interrupt it.
+func (s *Supervisor) scanFor(ctx context.Context, target string) (bluetooth.ScanResult, error) {
+	found := make(chan bluetooth.ScanResult, 1)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanErr <- s.adapter.Scan(func(adapter *bluetooth.Adapter, device bluetooth.ScanResult) {
+			if matchesTarget(target, device) {
+				adapter.StopScan()
+				found <- device
+			}
+		})
+	}()
+
+	select {
+	case device := <-found:
+		<-scanErr // Scan always returns once StopScan is called; drain so the goroutine doesn't leak.
+		return device, nil
+	case err := <-scanErr:
+		if err != nil {
+			return bluetooth.ScanResult{}, fmt.Errorf("scanning for %s: %w", target, err)
+		}
+		return bluetooth.ScanResult{}, fmt.Errorf("scanning for %s: scan ended without finding a match", target)
+	case <-ctx.Done():
+		s.adapter.StopScan()
+		<-scanErr // wait for the scanning goroutine to exit before returning
+		return bluetooth.ScanResult{}, ctx.Err()
+	}
+}
+
+func discoverCharacteristic(dev bluetooth.Device, driver bms.Driver) (*bluetooth.DeviceCharacteristic, error) {
+	services, err := dev.DiscoverServices(nil)
+	if err != nil {
+		return nil, fmt.Errorf("discovering services: %w", err)
+	}
+
+	for _, service := range services {
+		chars, err := service.DiscoverCharacteristics(nil)
+		if err != nil {
+			return nil, fmt.Errorf("discovering characteristics: %w", err)
+		}
+		for _, c := range chars {
+			if c.UUID().String() == driver.CharUUID().String() {
+				char := c
+				return &char, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("BMS characteristic %s not found", driver.CharUUID().String())
+}
+
+// pollOnce sends every command the driver knows and publishes a Sample --
+// successful or not -- for each. A failed write is treated as a lost
+// connection: pollOnce publishes that error, skips the remaining commands
+// and returns it, so connectAndPoll returns and the target is reconnected.
+func (s *Supervisor) pollOnce(ctx context.Context, address string, driver bms.Driver, char *bluetooth.DeviceCharacteristic, framer bms.Framer) error {
+	for name, cmd := range driver.Commands() {
+		cmdID, ok := driver.CommandID(cmd)
+		if !ok {
+			s.samples <- Sample{Address: address, Err: fmt.Errorf("command %s has no command id", name)}
+			continue
+		}
+
+		if _, err := char.WriteWithoutResponse(cmd); err != nil {
+			err = fmt.Errorf("sending %s: %w", name, err)
+			s.samples <- Sample{Address: address, Err: err}
+			return err
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, defaultResponseWait)
+		payload, err := framer.WaitForCommand(waitCtx, cmdID)
+		cancel()
+		if err != nil {
+			s.samples <- Sample{Address: address, Err: fmt.Errorf("waiting for %s: %w", name, err)}
+			continue
+		}
+
+		info, err := driver.Parse(name, payload)
+		if err != nil {
+			s.samples <- Sample{Address: address, Err: fmt.Errorf("parsing %s: %w", name, err)}
+			continue
+		}
+
+		s.samples <- Sample{Address: address, Info: info}
+	}
+	return nil
+}
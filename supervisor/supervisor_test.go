@@ -0,0 +1,56 @@
+package supervisor
+
+import (
+	"testing"
+
+	"tinygo.org/x/bluetooth"
+)
+
+// fakeAdvertisement is a minimal bluetooth.AdvertisementPayload for tests
+// that only need a LocalName.
+type fakeAdvertisement struct {
+	localName string
+}
+
+func (f fakeAdvertisement) LocalName() string                                     { return f.localName }
+func (f fakeAdvertisement) HasServiceUUID(bluetooth.UUID) bool                    { return false }
+func (f fakeAdvertisement) ServiceUUIDs() []bluetooth.UUID                        { return nil }
+func (f fakeAdvertisement) Bytes() []byte                                         { return nil }
+func (f fakeAdvertisement) ManufacturerData() []bluetooth.ManufacturerDataElement { return nil }
+func (f fakeAdvertisement) ServiceData() []bluetooth.ServiceDataElement           { return nil }
+
+func scanResult(t *testing.T, address, localName string) bluetooth.ScanResult {
+	t.Helper()
+	mac, err := bluetooth.ParseMAC(address)
+	if err != nil {
+		t.Fatalf("ParseMAC(%q): %v", address, err)
+	}
+	return bluetooth.ScanResult{
+		Address:              bluetooth.Address{MACAddress: bluetooth.MACAddress{MAC: mac}},
+		AdvertisementPayload: fakeAdvertisement{localName: localName},
+	}
+}
+
+func TestMatchesTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		address string
+		local   string
+		want    bool
+	}{
+		{"exact address match", "AA:BB:CC:DD:EE:FF", "AA:BB:CC:DD:EE:FF", "SomeOtherName", true},
+		{"local name substring match", "BNN160", "11:22:33:44:55:66", "P-24100BNN160", true},
+		{"no match", "BNN160", "11:22:33:44:55:66", "JBD-1234", false},
+		{"empty target matches nothing by name", "", "11:22:33:44:55:66", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := scanResult(t, tt.address, tt.local)
+			if got := matchesTarget(tt.target, device); got != tt.want {
+				t.Errorf("matchesTarget(%q, %+v) = %v, want %v", tt.target, device, got, tt.want)
+			}
+		})
+	}
+}